@@ -0,0 +1,70 @@
+// Copyright 2020 Nexus Operator and/or its authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NexusConditionType is the type of a condition reported on a Nexus' status.
+type NexusConditionType string
+
+// NexusCondition represents a single observation point in time about the
+// state of a Nexus instance, following the standard Kubernetes conditions
+// pattern.
+type NexusCondition struct {
+	Type               NexusConditionType     `json:"type"`
+	Status             corev1.ConditionStatus `json:"status"`
+	LastTransitionTime metav1.Time            `json:"lastTransitionTime,omitempty"`
+	Reason             string                 `json:"reason,omitempty"`
+	Message            string                 `json:"message,omitempty"`
+}
+
+// SetCondition sets the given condition on the status, updating
+// LastTransitionTime only when the status actually changes and replacing
+// any previous condition of the same type.
+func (s *NexusStatus) SetCondition(condType NexusConditionType, status corev1.ConditionStatus, reason, message string) {
+	now := metav1.Now()
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == condType {
+			if s.Conditions[i].Status != status {
+				s.Conditions[i].LastTransitionTime = now
+			}
+			s.Conditions[i].Status = status
+			s.Conditions[i].Reason = reason
+			s.Conditions[i].Message = message
+			return
+		}
+	}
+	s.Conditions = append(s.Conditions, NexusCondition{
+		Type:               condType,
+		Status:             status,
+		LastTransitionTime: now,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// RemoveCondition removes any condition of the given type from the status,
+// if present.
+func (s *NexusStatus) RemoveCondition(condType NexusConditionType) {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == condType {
+			s.Conditions = append(s.Conditions[:i], s.Conditions[i+1:]...)
+			return
+		}
+	}
+}
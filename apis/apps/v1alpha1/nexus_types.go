@@ -0,0 +1,170 @@
+// Copyright 2020 Nexus Operator and/or its authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NexusPersistenceBackend selects what kind of storage backs a Nexus
+// instance's data directory.
+type NexusPersistenceBackend string
+
+const (
+	// PersistenceBackendFilesystem stores Nexus' data directory on a PVC.
+	// This is the default.
+	PersistenceBackendFilesystem NexusPersistenceBackend = "filesystem"
+	// PersistenceBackendS3 configures Nexus 3's blob store to use an S3
+	// bucket provisioned via an ObjectBucketClaim (e.g. backed by NooBaa)
+	// instead of a PVC.
+	PersistenceBackendS3 NexusPersistenceBackend = "s3"
+)
+
+// NexusPersistence holds the configuration for the persistent volume claim
+// backing a Nexus instance's data directory.
+type NexusPersistence struct {
+	// Persistent marks this Nexus instance as using persistent storage.
+	// When unset, the Nexus data directory lives in an EmptyDir and is lost
+	// whenever the pod is rescheduled.
+	// +optional
+	Persistent bool `json:"persistent,omitempty"`
+
+	// Backend selects the kind of storage used: "filesystem" (default, a
+	// PVC) or "s3" (an ObjectBucketClaim). The two are mutually exclusive;
+	// fields only relevant to one backend are ignored under the other.
+	// +optional
+	// +kubebuilder:validation:Enum=filesystem;s3
+	Backend NexusPersistenceBackend `json:"backend,omitempty"`
+
+	// VolumeSize is the amount of storage requested for the Nexus data
+	// volume (e.g. "10Gi"). Only used when Persistent is true.
+	// +optional
+	VolumeSize string `json:"volumeSize,omitempty"`
+
+	// DataSource seeds the Nexus data volume from an existing source
+	// instead of provisioning it empty, e.g. a VolumeSnapshot, a clone of
+	// another PVC, or a custom populator CR. The referenced kind/apiGroup
+	// must be allow-listed, see CustomDataSourceAPIGroups.
+	// +optional
+	DataSource *corev1.TypedLocalObjectReference `json:"dataSource,omitempty"`
+
+	// CustomDataSourceAPIGroups lists additional API groups, beyond the
+	// built-in VolumeSnapshot and PersistentVolumeClaim sources, that
+	// DataSource is allowed to reference. Use this to allow a custom volume
+	// populator CRD.
+	// +optional
+	CustomDataSourceAPIGroups []string `json:"customDataSourceAPIGroups,omitempty"`
+
+	// Snapshot configures periodic VolumeSnapshots of the Nexus data
+	// volume. Leave nil to disable snapshotting.
+	// +optional
+	Snapshot *NexusPersistenceSnapshot `json:"snapshot,omitempty"`
+
+	// RestoreFromSnapshot names a VolumeSnapshot to restore the Nexus data
+	// volume from. It only takes effect the first time the PVC is created;
+	// it is ignored on every subsequent reconcile and is cleared from the
+	// Nexus CR once consumed.
+	// +optional
+	RestoreFromSnapshot string `json:"restoreFromSnapshot,omitempty"`
+
+	// ObjectBucketClaim configures the ObjectBucketClaim generated for the
+	// s3 persistence backend. Only used when Backend is
+	// PersistenceBackendS3.
+	// +optional
+	ObjectBucketClaim *NexusPersistenceObjectBucketClaim `json:"objectBucketClaim,omitempty"`
+}
+
+// NexusPersistenceObjectBucketClaim configures the ObjectBucketClaim
+// generated for the s3 persistence backend.
+type NexusPersistenceObjectBucketClaim struct {
+	// StorageClassName is the bucket-provisioning StorageClass the claim is
+	// submitted against (e.g. a NooBaa-backed class), the same way a PVC's
+	// storageClassName selects a CSI driver. Required for the claim to ever
+	// be bound by a provisioner.
+	StorageClassName string `json:"storageClassName"`
+}
+
+// NexusPersistenceSnapshot configures periodic snapshotting of a Nexus
+// instance's data volume.
+type NexusPersistenceSnapshot struct {
+	// Schedule is a standard cron expression (e.g. "0 0 * * *") controlling
+	// how often a snapshot is taken.
+	Schedule string `json:"schedule"`
+
+	// Retention is the number of snapshots to keep. Snapshots beyond this
+	// count are pruned, oldest first. Defaults to 1 if unset or <= 0; there
+	// is currently no way to disable pruning.
+	// +optional
+	Retention int `json:"retention,omitempty"`
+
+	// VolumeSnapshotClassName is the VolumeSnapshotClass used for the
+	// snapshots. Leave empty to use the cluster's default class.
+	// +optional
+	VolumeSnapshotClassName string `json:"volumeSnapshotClassName,omitempty"`
+}
+
+// NexusSpec defines the desired state of Nexus.
+type NexusSpec struct {
+	// Image is the Nexus image to deploy.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Replicas is the number of Nexus pod replicas.
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Resources is the resource requirements for the Nexus container.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// ServiceAccountName is the name of the ServiceAccount the Nexus pod
+	// should run as.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// Persistence configures the persistent storage used by this Nexus
+	// instance.
+	// +optional
+	Persistence NexusPersistence `json:"persistence,omitempty"`
+}
+
+// NexusStatus defines the observed state of Nexus.
+type NexusStatus struct {
+	// Conditions is the set of conditions observed for this Nexus instance.
+	// +optional
+	Conditions []NexusCondition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// Nexus is the Schema for the nexus API.
+type Nexus struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NexusSpec   `json:"spec,omitempty"`
+	Status NexusStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NexusList contains a list of Nexus.
+type NexusList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Nexus `json:"items"`
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 Nexus Operator and/or its authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persistence
+
+import (
+	"reflect"
+	"strings"
+
+	obv1alpha1 "github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// operatorAnnotationPrefix namespaces the labels/annotations this operator
+// itself manages on a PVC, so user- or controller-set ones outside of it
+// (e.g. pv.kubernetes.io/bind-completed) are never flagged as drift.
+const operatorAnnotationPrefix = "apps.m88i.io/"
+
+// pvcComparator reports whether deployed already matches required, looking
+// only at the fields this operator actually owns. A PVC's spec is largely
+// immutable once bound, and the API server sets a number of fields itself
+// (volumeName, phase, a defaulted storageClassName, provisioner
+// annotations...) that would otherwise cause the generic reflect-based
+// comparator to flag perpetual, unfixable drift.
+func pvcComparator(deployed, required client.Object) bool {
+	deployedPVC := deployed.(*corev1.PersistentVolumeClaim)
+	requiredPVC := required.(*corev1.PersistentVolumeClaim)
+
+	if !reflect.DeepEqual(deployedPVC.Spec.AccessModes, requiredPVC.Spec.AccessModes) {
+		return false
+	}
+
+	deployedSize := deployedPVC.Spec.Resources.Requests[corev1.ResourceStorage]
+	requiredSize := requiredPVC.Spec.Resources.Requests[corev1.ResourceStorage]
+	if deployedSize.Cmp(requiredSize) != 0 {
+		return false
+	}
+
+	if requiredPVC.Spec.StorageClassName != nil && *requiredPVC.Spec.StorageClassName != "" {
+		if deployedPVC.Spec.StorageClassName == nil || *deployedPVC.Spec.StorageClassName != *requiredPVC.Spec.StorageClassName {
+			return false
+		}
+	}
+
+	if requiredPVC.Spec.VolumeMode != nil {
+		if deployedPVC.Spec.VolumeMode == nil || *deployedPVC.Spec.VolumeMode != *requiredPVC.Spec.VolumeMode {
+			return false
+		}
+	}
+
+	if !reflect.DeepEqual(deployedPVC.Spec.Selector, requiredPVC.Spec.Selector) {
+		return false
+	}
+
+	if !ownedSubsetEqual(deployedPVC.Labels, requiredPVC.Labels) {
+		return false
+	}
+	if !ownedSubsetEqual(deployedPVC.Annotations, requiredPVC.Annotations) {
+		return false
+	}
+
+	return true
+}
+
+// obcComparator reports whether deployed already matches required, looking
+// only at the fields this operator actually owns. Once lib-bucket-provisioner
+// binds a claim, it writes the resolved bucket name back onto
+// spec.bucketName and fills in spec.ObjectBucketName; comparing those against
+// our (empty, GenerateBucketName-only) required object would otherwise flag
+// every bound claim as drifted forever, the same class of bug pvcComparator
+// exists to avoid for PVCs.
+func obcComparator(deployed, required client.Object) bool {
+	deployedOBC := deployed.(*obv1alpha1.ObjectBucketClaim)
+	requiredOBC := required.(*obv1alpha1.ObjectBucketClaim)
+
+	if deployedOBC.Spec.StorageClassName != requiredOBC.Spec.StorageClassName {
+		return false
+	}
+	if deployedOBC.Spec.GenerateBucketName != requiredOBC.Spec.GenerateBucketName {
+		return false
+	}
+	if !reflect.DeepEqual(deployedOBC.Spec.AdditionalConfig, requiredOBC.Spec.AdditionalConfig) {
+		return false
+	}
+
+	return true
+}
+
+// ownedSubsetEqual compares only the keys under operatorAnnotationPrefix,
+// ignoring anything else an external controller or the API server may have
+// added to the map.
+func ownedSubsetEqual(deployed, required map[string]string) bool {
+	for k, v := range required {
+		if !strings.HasPrefix(k, operatorAnnotationPrefix) {
+			continue
+		}
+		if deployed[k] != v {
+			return false
+		}
+	}
+	for k, v := range deployed {
+		if !strings.HasPrefix(k, operatorAnnotationPrefix) {
+			continue
+		}
+		if required[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+// Copyright 2020 Nexus Operator and/or its authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persistence
+
+import (
+	ctx "context"
+	"fmt"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	volumeSnapshotKind     = "VolumeSnapshot"
+	volumeSnapshotAPIGroup = "snapshot.storage.k8s.io"
+	pvcKind                = "PersistentVolumeClaim"
+)
+
+// dataSourceAllowed reports whether kind/apiGroup is one this operator will
+// populate a PVC from: the built-in VolumeSnapshot and PersistentVolumeClaim
+// sources, or one of the custom populator API groups the Nexus CR
+// explicitly opted into.
+func (m *Manager) dataSourceAllowed(kind, apiGroup string) bool {
+	if kind == volumeSnapshotKind && apiGroup == volumeSnapshotAPIGroup {
+		return true
+	}
+	if kind == pvcKind && apiGroup == "" {
+		return true
+	}
+	for _, allowed := range m.nexus.Spec.Persistence.CustomDataSourceAPIGroups {
+		if allowed == apiGroup {
+			return true
+		}
+	}
+	return false
+}
+
+// isBuiltinDataSource reports whether kind/apiGroup is one of the two
+// sources core Kubernetes understands natively (VolumeSnapshot,
+// PersistentVolumeClaim), as opposed to a third-party populator CRD.
+func isBuiltinDataSource(kind, apiGroup string) bool {
+	return (kind == volumeSnapshotKind && apiGroup == volumeSnapshotAPIGroup) ||
+		(kind == pvcKind && apiGroup == "")
+}
+
+// reconcileDataSource validates the configured data source and, once its
+// source object is ready to be consumed, wires pvc up to it. It reports
+// whether the source is ready yet; when it isn't, the caller should hold off
+// creating the PVC until a later reconcile.
+func (m *Manager) reconcileDataSource(pvc *corev1.PersistentVolumeClaim) (bool, error) {
+	ds := m.nexus.Spec.Persistence.DataSource
+	apiGroup := ""
+	if ds.APIGroup != nil {
+		apiGroup = *ds.APIGroup
+	}
+
+	if !m.dataSourceAllowed(ds.Kind, apiGroup) {
+		return false, fmt.Errorf("persistence data source kind %q (apiGroup %q) is not allow-listed; add its apiGroup to spec.persistence.customDataSourceAPIGroups if this is a custom populator", ds.Kind, apiGroup)
+	}
+
+	ready, err := m.isDataSourceReady(ds.Kind, apiGroup, ds.Name)
+	if err != nil {
+		return false, err
+	}
+
+	if !ready {
+		m.nexus.Status.SetCondition(
+			ConditionSourcePopulationPending,
+			corev1.ConditionTrue,
+			"WaitingForSource",
+			fmt.Sprintf("waiting for %s %q to become ready before creating the Nexus PVC", ds.Kind, ds.Name))
+		return false, nil
+	}
+
+	pvc.Spec.DataSource = ds.DeepCopy()
+	if !isBuiltinDataSource(ds.Kind, apiGroup) {
+		// third-party populator controllers watch dataSourceRef, not the
+		// legacy dataSource field, and the API server's AnyVolumeDataSource
+		// validation only treats a foreign apiGroup as valid there.
+		pvc.Spec.DataSourceRef = &corev1.TypedObjectReference{
+			APIGroup: ds.APIGroup,
+			Kind:     ds.Kind,
+			Name:     ds.Name,
+		}
+	}
+	m.nexus.Status.RemoveCondition(ConditionSourcePopulationPending)
+	m.nexus.Status.SetCondition(
+		ConditionSourcePopulationComplete,
+		corev1.ConditionTrue,
+		"SourceReady",
+		fmt.Sprintf("PVC will be populated from %s %q", ds.Kind, ds.Name))
+	return true, nil
+}
+
+// isDataSourceReady checks whether the referenced source object is in a
+// state the API server will accept as a data source. Custom populator kinds
+// are assumed ready immediately, since the operator has no generic way to
+// know how to introspect their readiness.
+func (m *Manager) isDataSourceReady(kind, apiGroup, name string) (bool, error) {
+	switch {
+	case kind == volumeSnapshotKind && apiGroup == volumeSnapshotAPIGroup:
+		snap := &snapshotv1.VolumeSnapshot{}
+		key := client.ObjectKey{Name: name, Namespace: m.nexus.Namespace}
+		if err := m.client.Get(ctx.TODO(), key, snap); err != nil {
+			if errors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		return snap.Status != nil && snap.Status.ReadyToUse != nil && *snap.Status.ReadyToUse, nil
+
+	case kind == pvcKind && apiGroup == "":
+		source := &corev1.PersistentVolumeClaim{}
+		key := client.ObjectKey{Name: name, Namespace: m.nexus.Namespace}
+		if err := m.client.Get(ctx.TODO(), key, source); err != nil {
+			if errors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		return source.Status.Phase == corev1.ClaimBound, nil
+
+	default:
+		// a custom populator: the operator doesn't know this CRD's status
+		// shape, so it defers readiness entirely to the populator
+		// controller and the API server's own admission checks.
+		return true, nil
+	}
+}
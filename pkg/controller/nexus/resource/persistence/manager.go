@@ -0,0 +1,287 @@
+// Copyright 2020 Nexus Operator and/or its authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package persistence reconciles the storage resources backing a Nexus
+// instance's data directory.
+package persistence
+
+import (
+	ctx "context"
+	"fmt"
+	"reflect"
+
+	obv1alpha1 "github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/m88i/nexus-operator/apis/apps/v1alpha1"
+)
+
+const (
+	// ConditionPVCResizing is set on the Nexus status to True when a size
+	// increase requested via Spec.Persistence.VolumeSize is passed on to the
+	// PVC, to False when such a request is refused, and removed once the
+	// deployed PVC's spec requests the same size as Spec.Persistence.VolumeSize.
+	ConditionPVCResizing v1alpha1.NexusConditionType = "PersistentVolumeClaimResizing"
+	// ConditionFileSystemResizePending is set on the Nexus status together
+	// with a True ConditionPVCResizing, and removed along with it. It is not
+	// read from the PVC's own status conditions, so it does not track when
+	// the underlying filesystem has actually caught up with the new size.
+	ConditionFileSystemResizePending v1alpha1.NexusConditionType = "FileSystemResizePending"
+
+	// ConditionSourcePopulationPending is set while the PVC's data source
+	// has not yet become ready to be consumed.
+	ConditionSourcePopulationPending v1alpha1.NexusConditionType = "SourcePopulationPending"
+	// ConditionSourcePopulationComplete is set once the PVC's data source
+	// has become ready and the PVC has been wired up to consume it.
+	ConditionSourcePopulationComplete v1alpha1.NexusConditionType = "SourcePopulationComplete"
+)
+
+// Manager is responsible for reconciling the persistent volume claim used by
+// a Nexus instance.
+type Manager struct {
+	nexus  *v1alpha1.Nexus
+	client client.Client
+}
+
+// NewManager creates a new persistence Manager for the given Nexus instance.
+func NewManager(nexus *v1alpha1.Nexus, client client.Client) *Manager {
+	return &Manager{nexus: nexus, client: client}
+}
+
+// GetRequiredResources returns the resources required to fulfil the
+// persistence configuration of this Nexus instance: a single PVC when using
+// the filesystem backend, a single ObjectBucketClaim when using s3, nothing
+// when persistence is disabled. The two backends are mutually exclusive.
+//
+// When a PVC is already deployed and the requested VolumeSize has grown, the
+// returned PVC carries the new size so the generic reconciler patches
+// spec.resources.requests.storage on the existing object, provided the PVC's
+// StorageClass allows volume expansion; otherwise the deployed size is kept
+// and the refusal is surfaced as a condition. Shrink requests
+// are refused (Kubernetes does not support shrinking a PVC) and are instead
+// surfaced as a condition on the Nexus status rather than failing the whole
+// reconcile loop.
+func (m *Manager) GetRequiredResources() ([]client.Object, error) {
+	if !m.nexus.Spec.Persistence.Persistent {
+		return nil, nil
+	}
+
+	if m.nexus.Spec.Persistence.Backend == v1alpha1.PersistenceBackendS3 {
+		return m.getRequiredResourcesS3()
+	}
+
+	pvc, err := m.newPVC()
+	if err != nil {
+		return nil, err
+	}
+
+	deployed, err := m.getDeployedPVC()
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if deployed != nil {
+		if err := m.reconcileSize(deployed, pvc); err != nil {
+			return nil, err
+		}
+		// dataSource and volumeMode are immutable once the PVC exists; carry
+		// the deployed values forward instead of handing the reconciler a
+		// required object that looks like it wants them cleared.
+		pvc.Spec.DataSource = deployed.Spec.DataSource.DeepCopy()
+		pvc.Spec.DataSourceRef = deployed.Spec.DataSourceRef.DeepCopy()
+		pvc.Spec.VolumeMode = deployed.Spec.VolumeMode
+		return []client.Object{pvc}, nil
+	}
+
+	// RestoreFromSnapshot is a one-shot convenience over DataSource: it only
+	// applies to the initial PVC creation and is cleared immediately after,
+	// so it has no effect on later reconciles even if the field lingers on
+	// the Nexus CR.
+	if m.nexus.Spec.Persistence.RestoreFromSnapshot != "" && m.nexus.Spec.Persistence.DataSource == nil {
+		name := m.nexus.Spec.Persistence.RestoreFromSnapshot
+		apiGroup := volumeSnapshotAPIGroup
+		m.nexus.Spec.Persistence.DataSource = &corev1.TypedLocalObjectReference{
+			Kind:     volumeSnapshotKind,
+			APIGroup: &apiGroup,
+			Name:     name,
+		}
+		m.nexus.Spec.Persistence.RestoreFromSnapshot = ""
+	}
+
+	// the data source is only relevant the first time the PVC is created:
+	// spec.dataSource is immutable afterwards.
+	if m.nexus.Spec.Persistence.DataSource != nil {
+		ready, err := m.reconcileDataSource(pvc)
+		if err != nil {
+			return nil, err
+		}
+		if !ready {
+			return nil, nil
+		}
+	}
+
+	return []client.Object{pvc}, nil
+}
+
+// reconcileSize compares the requested volume size against the deployed
+// PVC's, refusing shrinks and no-op'ing when the StorageClass does not
+// support expansion. Either way, the resulting desired size is written into
+// required so the caller only ever has a single PVC object to reconcile.
+func (m *Manager) reconcileSize(deployed, required *corev1.PersistentVolumeClaim) error {
+	deployedSize := deployed.Spec.Resources.Requests[corev1.ResourceStorage]
+	requiredSize := required.Spec.Resources.Requests[corev1.ResourceStorage]
+
+	cmp := requiredSize.Cmp(deployedSize)
+	if cmp == 0 {
+		m.nexus.Status.RemoveCondition(ConditionPVCResizing)
+		m.nexus.Status.RemoveCondition(ConditionFileSystemResizePending)
+		required.Spec.Resources.Requests[corev1.ResourceStorage] = deployedSize
+		return nil
+	}
+
+	if cmp < 0 {
+		// Kubernetes does not allow shrinking a PVC. Keep the deployed size
+		// and record why the requested size was ignored, rather than
+		// failing the reconcile loop over it.
+		m.nexus.Status.SetCondition(
+			ConditionPVCResizing,
+			corev1.ConditionFalse,
+			"VolumeSizeShrinkRefused",
+			"spec.persistence.volumeSize is smaller than the deployed PVC's size; shrinking a PVC is not supported and this request has been ignored")
+		required.Spec.Resources.Requests[corev1.ResourceStorage] = deployedSize
+		return nil
+	}
+
+	expandable, err := m.storageClassAllowsExpansion(deployed.Spec.StorageClassName)
+	if err != nil {
+		return err
+	}
+	if !expandable {
+		m.nexus.Status.SetCondition(
+			ConditionPVCResizing,
+			corev1.ConditionFalse,
+			"StorageClassDoesNotAllowExpansion",
+			"spec.persistence.volumeSize was increased but the PVC's StorageClass does not set allowVolumeExpansion; the request has been ignored")
+		required.Spec.Resources.Requests[corev1.ResourceStorage] = deployedSize
+		return nil
+	}
+
+	m.nexus.Status.SetCondition(
+		ConditionPVCResizing,
+		corev1.ConditionTrue,
+		"Resizing",
+		"the PVC is being resized to match spec.persistence.volumeSize")
+	m.nexus.Status.SetCondition(
+		ConditionFileSystemResizePending,
+		corev1.ConditionTrue,
+		"Resizing",
+		"waiting for the filesystem to catch up with the new PVC size")
+	return nil
+}
+
+// storageClassAllowsExpansion looks up the named StorageClass and reports
+// whether it has allowVolumeExpansion set. A nil/empty name (the cluster
+// default StorageClass) is treated as not expandable, since the operator
+// can't be sure which StorageClass will actually be used.
+func (m *Manager) storageClassAllowsExpansion(name *string) (bool, error) {
+	if name == nil || *name == "" {
+		return false, nil
+	}
+
+	sc := &storagev1.StorageClass{}
+	if err := m.client.Get(ctx.TODO(), client.ObjectKey{Name: *name}, sc); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return sc.AllowVolumeExpansion != nil && *sc.AllowVolumeExpansion, nil
+}
+
+// newPVC builds the PVC this Nexus instance requires, independently of
+// whether one is already deployed.
+func (m *Manager) newPVC() (*corev1.PersistentVolumeClaim, error) {
+	size, err := resource.ParseQuantity(m.nexus.Spec.Persistence.VolumeSize)
+	if err != nil {
+		return nil, fmt.Errorf("spec.persistence.volumeSize %q is not a valid quantity: %w", m.nexus.Spec.Persistence.VolumeSize, err)
+	}
+
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      m.nexus.Name,
+			Namespace: m.nexus.Namespace,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: size,
+				},
+			},
+		},
+	}, nil
+}
+
+// GetDeployedResources returns the PVC or ObjectBucketClaim currently
+// deployed for this Nexus instance, depending on its persistence backend, if
+// any.
+func (m *Manager) GetDeployedResources() ([]client.Object, error) {
+	if m.nexus.Spec.Persistence.Backend == v1alpha1.PersistenceBackendS3 {
+		return m.getDeployedResourcesS3()
+	}
+
+	var resources []client.Object
+	if pvc, err := m.getDeployedPVC(); err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+	} else {
+		resources = append(resources, pvc)
+	}
+	return resources, nil
+}
+
+func (m *Manager) getDeployedPVC() (*corev1.PersistentVolumeClaim, error) {
+	pvc := &corev1.PersistentVolumeClaim{}
+	key := client.ObjectKey{Name: m.nexus.Name, Namespace: m.nexus.Namespace}
+	if err := m.client.Get(ctx.TODO(), key, pvc); err != nil {
+		return nil, err
+	}
+	return pvc, nil
+}
+
+// GetCustomComparator returns the custom comparator function used to
+// determine whether a deployed resource of type t has drifted from the
+// required one, or nil if the generic reflect-based comparator should be
+// used instead.
+func (m *Manager) GetCustomComparator(t reflect.Type) func(deployed, required client.Object) bool {
+	return m.GetCustomComparators()[t]
+}
+
+// GetCustomComparators returns every custom comparator function offered by
+// this manager, keyed by the type they apply to.
+func (m *Manager) GetCustomComparators() map[reflect.Type]func(deployed, required client.Object) bool {
+	if m.nexus.Spec.Persistence.Backend == v1alpha1.PersistenceBackendS3 {
+		return map[reflect.Type]func(deployed, required client.Object) bool{
+			reflect.TypeOf(&obv1alpha1.ObjectBucketClaim{}): obcComparator,
+		}
+	}
+	return map[reflect.Type]func(deployed, required client.Object) bool{
+		reflect.TypeOf(&corev1.PersistentVolumeClaim{}): pvcComparator,
+	}
+}
@@ -20,11 +20,15 @@ import (
 	"reflect"
 	"testing"
 
+	obv1alpha1 "github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
 	"github.com/m88i/nexus-operator/apis/apps/v1alpha1"
 	"github.com/m88i/nexus-operator/pkg/test"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -70,6 +74,244 @@ func TestManager_GetRequiredResources(t *testing.T) {
 	assert.True(t, test.ContainsType(resources, reflect.TypeOf(&corev1.PersistentVolumeClaim{})))
 }
 
+func TestManager_GetRequiredResources_InvalidVolumeSize(t *testing.T) {
+	mgr := &Manager{
+		nexus:  baseNexus.DeepCopy(),
+		client: test.NewFakeClientBuilder().Build(),
+	}
+	mgr.nexus.Spec.Persistence.Persistent = true
+	mgr.nexus.Spec.Persistence.VolumeSize = "not-a-quantity"
+
+	resources, err := mgr.GetRequiredResources()
+	assert.Error(t, err)
+	assert.Nil(t, resources)
+}
+
+func TestManager_GetRequiredResources_Backends(t *testing.T) {
+	t.Run("filesystem backend produces a PVC, not an OBC", func(t *testing.T) {
+		nexus := baseNexus.DeepCopy()
+		nexus.Spec.Persistence.Persistent = true
+		nexus.Spec.Persistence.VolumeSize = "10Gi"
+
+		mgr := &Manager{nexus: nexus, client: test.NewFakeClientBuilder().Build()}
+		resources, err := mgr.GetRequiredResources()
+		assert.NoError(t, err)
+		assert.Len(t, resources, 1)
+		assert.True(t, test.ContainsType(resources, reflect.TypeOf(&corev1.PersistentVolumeClaim{})))
+		assert.False(t, test.ContainsType(resources, reflect.TypeOf(&obv1alpha1.ObjectBucketClaim{})))
+	})
+
+	t.Run("s3 backend produces an OBC, not a PVC", func(t *testing.T) {
+		nexus := baseNexus.DeepCopy()
+		nexus.Spec.Persistence.Persistent = true
+		nexus.Spec.Persistence.Backend = v1alpha1.PersistenceBackendS3
+		nexus.Spec.Persistence.ObjectBucketClaim = &v1alpha1.NexusPersistenceObjectBucketClaim{StorageClassName: "noobaa-bucket-class"}
+
+		mgr := &Manager{nexus: nexus, client: test.NewFakeClientBuilder().Build()}
+		resources, err := mgr.GetRequiredResources()
+		assert.NoError(t, err)
+		assert.Len(t, resources, 1)
+		assert.True(t, test.ContainsType(resources, reflect.TypeOf(&obv1alpha1.ObjectBucketClaim{})))
+		assert.False(t, test.ContainsType(resources, reflect.TypeOf(&corev1.PersistentVolumeClaim{})))
+
+		obc := resources[0].(*obv1alpha1.ObjectBucketClaim)
+		assert.Equal(t, "noobaa-bucket-class", obc.Spec.StorageClassName)
+
+		deployed, err := mgr.GetDeployedResources()
+		assert.NoError(t, err)
+		assert.Len(t, deployed, 0)
+	})
+}
+
+func TestManager_GetRequiredResources_DataSource(t *testing.T) {
+	readyTrue := true
+	pvcAPIGroup := ""
+	snapAPIGroup := volumeSnapshotAPIGroup
+	customAPIGroup := "populator.example.io"
+
+	t.Run("VolumeSnapshot source not yet ready", func(t *testing.T) {
+		nexus := baseNexus.DeepCopy()
+		nexus.Spec.Persistence.Persistent = true
+		nexus.Spec.Persistence.VolumeSize = "10Gi"
+		nexus.Spec.Persistence.DataSource = &corev1.TypedLocalObjectReference{Kind: volumeSnapshotKind, APIGroup: &snapAPIGroup, Name: "my-snap"}
+
+		mgr := &Manager{nexus: nexus, client: test.NewFakeClientBuilder().Build()}
+		resources, err := mgr.GetRequiredResources()
+		assert.NoError(t, err)
+		assert.Len(t, resources, 0)
+		assert.True(t, hasCondition(nexus, ConditionSourcePopulationPending, corev1.ConditionTrue))
+	})
+
+	t.Run("VolumeSnapshot source ready", func(t *testing.T) {
+		nexus := baseNexus.DeepCopy()
+		nexus.Spec.Persistence.Persistent = true
+		nexus.Spec.Persistence.VolumeSize = "10Gi"
+		nexus.Spec.Persistence.DataSource = &corev1.TypedLocalObjectReference{Kind: volumeSnapshotKind, APIGroup: &snapAPIGroup, Name: "my-snap"}
+
+		client := test.NewFakeClientBuilder().Build()
+		snap := &snapshotv1.VolumeSnapshot{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-snap", Namespace: nexus.Namespace},
+			Status:     &snapshotv1.VolumeSnapshotStatus{ReadyToUse: &readyTrue},
+		}
+		assert.NoError(t, client.Create(ctx.TODO(), snap))
+
+		mgr := &Manager{nexus: nexus, client: client}
+		resources, err := mgr.GetRequiredResources()
+		assert.NoError(t, err)
+		assert.Len(t, resources, 1)
+		pvc := resources[0].(*corev1.PersistentVolumeClaim)
+		assert.Equal(t, volumeSnapshotKind, pvc.Spec.DataSource.Kind)
+		// built-in sources don't need dataSourceRef
+		assert.Nil(t, pvc.Spec.DataSourceRef)
+		assert.True(t, hasCondition(nexus, ConditionSourcePopulationComplete, corev1.ConditionTrue))
+	})
+
+	t.Run("PersistentVolumeClaim source not bound", func(t *testing.T) {
+		nexus := baseNexus.DeepCopy()
+		nexus.Spec.Persistence.Persistent = true
+		nexus.Spec.Persistence.VolumeSize = "10Gi"
+		nexus.Spec.Persistence.DataSource = &corev1.TypedLocalObjectReference{Kind: pvcKind, APIGroup: &pvcAPIGroup, Name: "source-pvc"}
+
+		client := test.NewFakeClientBuilder().Build()
+		source := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "source-pvc", Namespace: nexus.Namespace},
+			Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+		}
+		assert.NoError(t, client.Create(ctx.TODO(), source))
+
+		mgr := &Manager{nexus: nexus, client: client}
+		resources, err := mgr.GetRequiredResources()
+		assert.NoError(t, err)
+		assert.Len(t, resources, 0)
+	})
+
+	t.Run("custom populator kind requires allow-listing", func(t *testing.T) {
+		nexus := baseNexus.DeepCopy()
+		nexus.Spec.Persistence.Persistent = true
+		nexus.Spec.Persistence.VolumeSize = "10Gi"
+		nexus.Spec.Persistence.DataSource = &corev1.TypedLocalObjectReference{Kind: "URLPopulator", APIGroup: &customAPIGroup, Name: "from-backup"}
+
+		mgr := &Manager{nexus: nexus, client: test.NewFakeClientBuilder().Build()}
+		_, err := mgr.GetRequiredResources()
+		assert.Error(t, err)
+
+		nexus.Spec.Persistence.CustomDataSourceAPIGroups = []string{customAPIGroup}
+		resources, err := mgr.GetRequiredResources()
+		assert.NoError(t, err)
+		assert.Len(t, resources, 1)
+
+		// custom populator controllers watch dataSourceRef, not dataSource
+		pvc := resources[0].(*corev1.PersistentVolumeClaim)
+		assert.NotNil(t, pvc.Spec.DataSource)
+		assert.NotNil(t, pvc.Spec.DataSourceRef)
+		assert.Equal(t, "URLPopulator", pvc.Spec.DataSourceRef.Kind)
+		assert.Equal(t, customAPIGroup, *pvc.Spec.DataSourceRef.APIGroup)
+		assert.Equal(t, "from-backup", pvc.Spec.DataSourceRef.Name)
+	})
+}
+
+func hasCondition(nexus *v1alpha1.Nexus, condType v1alpha1.NexusConditionType, status corev1.ConditionStatus) bool {
+	for _, cond := range nexus.Status.Conditions {
+		if cond.Type == condType && cond.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+func TestManager_GetRequiredResources_Resize(t *testing.T) {
+	trueVal := true
+	falseVal := false
+	scName := "expandable"
+
+	expandableSC := &storagev1.StorageClass{
+		ObjectMeta:           metav1.ObjectMeta{Name: scName},
+		AllowVolumeExpansion: &trueVal,
+	}
+	nonExpandableSC := &storagev1.StorageClass{
+		ObjectMeta:           metav1.ObjectMeta{Name: "non-expandable"},
+		AllowVolumeExpansion: &falseVal,
+	}
+
+	table := []struct {
+		name           string
+		deployedSize   string
+		requiredSize   string
+		storageClass   *string
+		wantSize       string
+		wantCondStatus corev1.ConditionStatus
+	}{
+		{
+			name:           "size increased on an expandable StorageClass",
+			deployedSize:   "10Gi",
+			requiredSize:   "20Gi",
+			storageClass:   &scName,
+			wantSize:       "20Gi",
+			wantCondStatus: corev1.ConditionTrue,
+		},
+		{
+			name:           "size increased on a non-expandable StorageClass",
+			deployedSize:   "10Gi",
+			requiredSize:   "20Gi",
+			storageClass:   &nonExpandableSC.Name,
+			wantSize:       "10Gi",
+			wantCondStatus: corev1.ConditionFalse,
+		},
+		{
+			name:           "size decreased is refused",
+			deployedSize:   "20Gi",
+			requiredSize:   "10Gi",
+			storageClass:   &scName,
+			wantSize:       "20Gi",
+			wantCondStatus: corev1.ConditionFalse,
+		},
+	}
+
+	for _, entry := range table {
+		t.Run(entry.name, func(t *testing.T) {
+			nexus := baseNexus.DeepCopy()
+			nexus.Spec.Persistence.Persistent = true
+			nexus.Spec.Persistence.VolumeSize = entry.requiredSize
+
+			client := test.NewFakeClientBuilder(expandableSC, nonExpandableSC).Build()
+			deployed := &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: nexus.Name, Namespace: nexus.Namespace},
+				Spec: corev1.PersistentVolumeClaimSpec{
+					StorageClassName: entry.storageClass,
+					DataSource:       &corev1.TypedLocalObjectReference{Kind: pvcKind, Name: "source-pvc"},
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(entry.deployedSize)},
+					},
+				},
+			}
+			assert.NoError(t, client.Create(ctx.TODO(), deployed))
+
+			mgr := &Manager{nexus: nexus, client: client}
+			resources, err := mgr.GetRequiredResources()
+			assert.NoError(t, err)
+			assert.Len(t, resources, 1)
+
+			pvc := resources[0].(*corev1.PersistentVolumeClaim)
+			gotSize := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+			wantSize := resource.MustParse(entry.wantSize)
+			assert.Zero(t, gotSize.Cmp(wantSize))
+
+			// dataSource is immutable once the PVC exists; a resize must not
+			// silently drop it from the required object
+			assert.Equal(t, deployed.Spec.DataSource, pvc.Spec.DataSource)
+
+			found := false
+			for _, cond := range nexus.Status.Conditions {
+				if cond.Type == ConditionPVCResizing {
+					found = true
+					assert.Equal(t, entry.wantCondStatus, cond.Status)
+				}
+			}
+			assert.True(t, found)
+		})
+	}
+}
+
 func TestManager_GetDeployedResources(t *testing.T) {
 	// first with no deployed resources
 	fakeClient := test.NewFakeClientBuilder().Build()
@@ -119,21 +361,142 @@ func TestManager_getDeployedPVC(t *testing.T) {
 }
 
 func TestManager_GetCustomComparator(t *testing.T) {
-	// the nexus and the client should have no effect on the
-	// comparator functions offered by the manager
-	mgr := &Manager{}
+	// the client should have no effect on the comparator functions offered
+	// by the manager, but the backend does
+	mgr := &Manager{nexus: baseNexus.DeepCopy()}
 
-	// there is no custom comparator function for PVCs
+	// the filesystem backend has a custom comparator function for PVCs
 	pvcComp := mgr.GetCustomComparator(reflect.TypeOf(&corev1.PersistentVolumeClaim{}))
-	assert.Nil(t, pvcComp)
+	assert.NotNil(t, pvcComp)
+
+	// but not for some other type
+	assert.Nil(t, mgr.GetCustomComparator(reflect.TypeOf(&corev1.Secret{})))
+
+	// under the s3 backend, the comparator is keyed on ObjectBucketClaim
+	// instead, not on PersistentVolumeClaim
+	mgr.nexus.Spec.Persistence.Backend = v1alpha1.PersistenceBackendS3
+	assert.Nil(t, mgr.GetCustomComparator(reflect.TypeOf(&corev1.PersistentVolumeClaim{})))
+	assert.NotNil(t, mgr.GetCustomComparator(reflect.TypeOf(&obv1alpha1.ObjectBucketClaim{})))
 }
 
 func TestManager_GetCustomComparators(t *testing.T) {
-	// the nexus and the client should have no effect on the
-	// comparator functions offered by the manager
-	mgr := &Manager{}
+	mgr := &Manager{nexus: baseNexus.DeepCopy()}
 
-	// there is no custom comparator function for PVCs
 	comparators := mgr.GetCustomComparators()
-	assert.Nil(t, comparators)
+	assert.Len(t, comparators, 1)
+	assert.Contains(t, comparators, reflect.TypeOf(&corev1.PersistentVolumeClaim{}))
+
+	mgr.nexus.Spec.Persistence.Backend = v1alpha1.PersistenceBackendS3
+	comparators = mgr.GetCustomComparators()
+	assert.Len(t, comparators, 1)
+	assert.Contains(t, comparators, reflect.TypeOf(&obv1alpha1.ObjectBucketClaim{}))
+}
+
+func TestPVCComparator(t *testing.T) {
+	storageClass := "standard"
+	volumeMode := corev1.PersistentVolumeFilesystem
+
+	baseDeployed := func() *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "nexus",
+				Namespace: "test",
+				Labels:    map[string]string{operatorAnnotationPrefix + "managed-by": "nexus-operator"},
+				Annotations: map[string]string{
+					"pv.kubernetes.io/bind-completed":               "yes",
+					"volume.beta.kubernetes.io/storage-provisioner": "kubernetes.io/aws-ebs",
+				},
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				StorageClassName: &storageClass,
+				VolumeMode:       &volumeMode,
+				VolumeName:       "pvc-1234",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+				},
+			},
+			Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+		}
+	}
+
+	baseRequired := func() *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "nexus",
+				Namespace: "test",
+				Labels:    map[string]string{operatorAnnotationPrefix + "managed-by": "nexus-operator"},
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				StorageClassName: &storageClass,
+				VolumeMode:       &volumeMode,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+				},
+			},
+		}
+	}
+
+	t.Run("bound PVC with only server-set fields differing", func(t *testing.T) {
+		assert.True(t, pvcComparator(baseDeployed(), baseRequired()))
+	})
+
+	t.Run("resized PVC", func(t *testing.T) {
+		deployed := baseDeployed()
+		required := baseRequired()
+		required.Spec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("20Gi")
+		assert.False(t, pvcComparator(deployed, required))
+	})
+
+	t.Run("PVC annotated by an external controller", func(t *testing.T) {
+		deployed := baseDeployed()
+		deployed.Annotations["some-external-controller.io/owner"] = "someone-else"
+		assert.True(t, pvcComparator(deployed, baseRequired()))
+	})
+
+	t.Run("operator-owned label drifted", func(t *testing.T) {
+		deployed := baseDeployed()
+		deployed.Labels[operatorAnnotationPrefix+"managed-by"] = "something-else"
+		assert.False(t, pvcComparator(deployed, baseRequired()))
+	})
+}
+
+func TestOBCComparator(t *testing.T) {
+	baseRequired := func() *obv1alpha1.ObjectBucketClaim {
+		return &obv1alpha1.ObjectBucketClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "nexus", Namespace: "test"},
+			Spec: obv1alpha1.ObjectBucketClaimSpec{
+				StorageClassName:   "noobaa-bucket-class",
+				GenerateBucketName: "nexus",
+			},
+		}
+	}
+
+	// once bound, lib-bucket-provisioner writes the resolved bucket name and
+	// the backing ObjectBucket's name onto the claim it reconciles
+	baseDeployed := func() *obv1alpha1.ObjectBucketClaim {
+		deployed := baseRequired()
+		deployed.Spec.BucketName = "nexus-a1b2c3"
+		deployed.Spec.ObjectBucketName = "obc-test-nexus"
+		return deployed
+	}
+
+	t.Run("bound claim with only provisioner-set fields differing", func(t *testing.T) {
+		assert.True(t, obcComparator(baseDeployed(), baseRequired()))
+	})
+
+	t.Run("StorageClassName drifted", func(t *testing.T) {
+		deployed := baseDeployed()
+		required := baseRequired()
+		required.Spec.StorageClassName = "some-other-class"
+		assert.False(t, obcComparator(deployed, required))
+	})
+
+	t.Run("AdditionalConfig drifted", func(t *testing.T) {
+		deployed := baseDeployed()
+		required := baseRequired()
+		required.Spec.AdditionalConfig = map[string]string{"bucketclass": "noobaa-default"}
+		assert.False(t, obcComparator(deployed, required))
+	})
 }
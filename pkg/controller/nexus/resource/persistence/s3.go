@@ -0,0 +1,120 @@
+// Copyright 2020 Nexus Operator and/or its authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persistence
+
+import (
+	ctx "context"
+
+	obv1alpha1 "github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// the lib-bucket-provisioner always projects the bucket's credentials and
+// connection info into a Secret and ConfigMap named after the claim.
+const (
+	bucketAccessKeyIDKey     = "AWS_ACCESS_KEY_ID"
+	bucketSecretAccessKeyKey = "AWS_SECRET_ACCESS_KEY"
+	bucketNameKey            = "BUCKET_NAME"
+	bucketHostKey            = "BUCKET_HOST"
+	bucketPortKey            = "BUCKET_PORT"
+)
+
+// getRequiredResourcesS3 builds the ObjectBucketClaim this Nexus instance
+// requires when using the s3 persistence backend. Unlike the filesystem
+// backend, an OBC is never resized in place, so there's no size diffing to
+// do here; it's still possible for the bucket provisioner to write values
+// back onto the claim's spec after binding, see obcComparator.
+func (m *Manager) getRequiredResourcesS3() ([]client.Object, error) {
+	return []client.Object{m.newOBC()}, nil
+}
+
+func (m *Manager) newOBC() *obv1alpha1.ObjectBucketClaim {
+	var storageClassName string
+	if obc := m.nexus.Spec.Persistence.ObjectBucketClaim; obc != nil {
+		storageClassName = obc.StorageClassName
+	}
+	return &obv1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      m.nexus.Name,
+			Namespace: m.nexus.Namespace,
+		},
+		Spec: obv1alpha1.ObjectBucketClaimSpec{
+			StorageClassName:   storageClassName,
+			GenerateBucketName: m.nexus.Name,
+		},
+	}
+}
+
+func (m *Manager) getDeployedOBC() (*obv1alpha1.ObjectBucketClaim, error) {
+	obc := &obv1alpha1.ObjectBucketClaim{}
+	key := client.ObjectKey{Name: m.nexus.Name, Namespace: m.nexus.Namespace}
+	if err := m.client.Get(ctx.TODO(), key, obc); err != nil {
+		return nil, err
+	}
+	return obc, nil
+}
+
+func (m *Manager) getDeployedResourcesS3() ([]client.Object, error) {
+	var resources []client.Object
+	if obc, err := m.getDeployedOBC(); err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+	} else {
+		resources = append(resources, obc)
+	}
+	return resources, nil
+}
+
+// BucketEnvVars returns the env vars a Nexus deployment needs to consume the
+// S3 bucket provisioned for it, sourced from the Secret/ConfigMap the
+// lib-bucket-provisioner projects alongside the ObjectBucketClaim. Only
+// meaningful when Spec.Persistence.Backend is PersistenceBackendS3.
+func BucketEnvVars(nexusName string) []corev1.EnvVar {
+	return []corev1.EnvVar{
+		envFromSecret("AWS_ACCESS_KEY_ID", nexusName, bucketAccessKeyIDKey),
+		envFromSecret("AWS_SECRET_ACCESS_KEY", nexusName, bucketSecretAccessKeyKey),
+		envFromConfigMap("BUCKET_NAME", nexusName, bucketNameKey),
+		envFromConfigMap("BUCKET_HOST", nexusName, bucketHostKey),
+		envFromConfigMap("BUCKET_PORT", nexusName, bucketPortKey),
+	}
+}
+
+func envFromSecret(envName, secretName, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: envName,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+				Key:                  key,
+			},
+		},
+	}
+}
+
+func envFromConfigMap(envName, configMapName, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: envName,
+		ValueFrom: &corev1.EnvVarSource{
+			ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: configMapName},
+				Key:                  key,
+			},
+		},
+	}
+}
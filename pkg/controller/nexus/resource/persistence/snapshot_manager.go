@@ -0,0 +1,150 @@
+// Copyright 2020 Nexus Operator and/or its authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persistence
+
+import (
+	ctx "context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	"github.com/robfig/cron/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/m88i/nexus-operator/apis/apps/v1alpha1"
+)
+
+// snapshotLabel marks the VolumeSnapshots this manager owns for a given
+// Nexus instance, so GetDeployedResources doesn't have to guess at naming.
+const snapshotLabel = operatorAnnotationPrefix + "nexus-name"
+
+// SnapshotManager reconciles the periodic VolumeSnapshots of a Nexus
+// instance's data volume, as configured by Spec.Persistence.Snapshot.
+type SnapshotManager struct {
+	nexus  *v1alpha1.Nexus
+	client client.Client
+}
+
+// NewSnapshotManager creates a new SnapshotManager for the given Nexus
+// instance.
+func NewSnapshotManager(nexus *v1alpha1.Nexus, client client.Client) *SnapshotManager {
+	return &SnapshotManager{nexus: nexus, client: client}
+}
+
+// GetRequiredResources returns the VolumeSnapshots that should exist for
+// this Nexus instance: the retained snapshots already deployed, plus a new
+// one when the configured schedule is due. Snapshots beyond the retention
+// count are left out, so the generic reconciler prunes them.
+func (s *SnapshotManager) GetRequiredResources() ([]client.Object, error) {
+	snap := s.nexus.Spec.Persistence.Snapshot
+	if snap == nil || s.nexus.Spec.Persistence.Backend == v1alpha1.PersistenceBackendS3 {
+		return nil, nil
+	}
+
+	deployed, err := s.getDeployedSnapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, err := cron.ParseStandard(snap.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid persistence.snapshot.schedule %q: %v", snap.Schedule, err)
+	}
+
+	due := len(deployed) == 0 || !schedule.Next(deployed[0].CreationTimestamp.Time).After(time.Now())
+
+	retention := snap.Retention
+	if retention <= 0 {
+		retention = 1
+	}
+
+	var required []client.Object
+	if due {
+		required = append(required, s.newSnapshot())
+		retention--
+	}
+	for i := 0; i < len(deployed) && i < retention; i++ {
+		required = append(required, &deployed[i])
+	}
+
+	return required, nil
+}
+
+func (s *SnapshotManager) newSnapshot() *snapshotv1.VolumeSnapshot {
+	snap := s.nexus.Spec.Persistence.Snapshot
+	var class *string
+	if snap.VolumeSnapshotClassName != "" {
+		class = &snap.VolumeSnapshotClassName
+	}
+	return &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: s.nexus.Name + "-",
+			Namespace:    s.nexus.Namespace,
+			Labels:       map[string]string{snapshotLabel: s.nexus.Name},
+		},
+		Spec: snapshotv1.VolumeSnapshotSpec{
+			Source: snapshotv1.VolumeSnapshotSource{
+				PersistentVolumeClaimName: &s.nexus.Name,
+			},
+			VolumeSnapshotClassName: class,
+		},
+	}
+}
+
+// GetDeployedResources returns the VolumeSnapshots currently deployed for
+// this Nexus instance.
+func (s *SnapshotManager) GetDeployedResources() ([]client.Object, error) {
+	deployed, err := s.getDeployedSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	resources := make([]client.Object, len(deployed))
+	for i := range deployed {
+		resources[i] = &deployed[i]
+	}
+	return resources, nil
+}
+
+// getDeployedSnapshots lists every VolumeSnapshot owned by this Nexus
+// instance, newest first.
+func (s *SnapshotManager) getDeployedSnapshots() ([]snapshotv1.VolumeSnapshot, error) {
+	list := &snapshotv1.VolumeSnapshotList{}
+	opts := []client.ListOption{
+		client.InNamespace(s.nexus.Namespace),
+		client.MatchingLabels{snapshotLabel: s.nexus.Name},
+	}
+	if err := s.client.List(ctx.TODO(), list, opts...); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[j].CreationTimestamp.Before(&list.Items[i].CreationTimestamp)
+	})
+	return list.Items, nil
+}
+
+// GetCustomComparator returns nil: VolumeSnapshots are immutable once
+// created, there's nothing for the generic reconciler to patch.
+func (s *SnapshotManager) GetCustomComparator(t reflect.Type) func(deployed, required client.Object) bool {
+	return nil
+}
+
+// GetCustomComparators returns nil, see GetCustomComparator.
+func (s *SnapshotManager) GetCustomComparators() map[reflect.Type]func(deployed, required client.Object) bool {
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2020 Nexus Operator and/or its authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persistence
+
+import (
+	ctx "context"
+	"reflect"
+	"testing"
+	"time"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	"github.com/m88i/nexus-operator/apis/apps/v1alpha1"
+	"github.com/m88i/nexus-operator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSnapshotManager_GetRequiredResources_Creation(t *testing.T) {
+	nexus := baseNexus.DeepCopy()
+	nexus.Spec.Persistence.Snapshot = &v1alpha1.NexusPersistenceSnapshot{Schedule: "0 0 * * *", Retention: 3}
+
+	mgr := NewSnapshotManager(nexus, test.NewFakeClientBuilder().Build())
+	resources, err := mgr.GetRequiredResources()
+	assert.NoError(t, err)
+	// no prior snapshot: one is due immediately
+	assert.Len(t, resources, 1)
+	assert.True(t, test.ContainsType(resources, reflect.TypeOf(&snapshotv1.VolumeSnapshot{})))
+}
+
+func TestSnapshotManager_GetRequiredResources_RetentionPruning(t *testing.T) {
+	nexus := baseNexus.DeepCopy()
+	nexus.Spec.Persistence.Snapshot = &v1alpha1.NexusPersistenceSnapshot{Schedule: "0 0 1 1 *", Retention: 2}
+
+	client := test.NewFakeClientBuilder().Build()
+	now := time.Now()
+	for i, age := range []time.Duration{0, time.Hour, 2 * time.Hour, 3 * time.Hour} {
+		snap := &snapshotv1.VolumeSnapshot{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "nexus-snap-" + string(rune('a'+i)),
+				Namespace:         nexus.Namespace,
+				Labels:            map[string]string{snapshotLabel: nexus.Name},
+				CreationTimestamp: metav1.NewTime(now.Add(-age)),
+			},
+		}
+		assert.NoError(t, client.Create(ctx.TODO(), snap))
+	}
+
+	mgr := NewSnapshotManager(nexus, client)
+	resources, err := mgr.GetRequiredResources()
+	assert.NoError(t, err)
+	// the Jan 1st schedule isn't due, so only the retained snapshots come back
+	assert.Len(t, resources, 2)
+}
+
+func TestManager_GetRequiredResources_RestoreFromSnapshotOnce(t *testing.T) {
+	nexus := baseNexus.DeepCopy()
+	nexus.Spec.Persistence.Persistent = true
+	nexus.Spec.Persistence.VolumeSize = "10Gi"
+	nexus.Spec.Persistence.RestoreFromSnapshot = "backup-snap"
+
+	readyTrue := true
+	client := test.NewFakeClientBuilder().Build()
+	snap := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup-snap", Namespace: nexus.Namespace},
+		Status:     &snapshotv1.VolumeSnapshotStatus{ReadyToUse: &readyTrue},
+	}
+	assert.NoError(t, client.Create(ctx.TODO(), snap))
+
+	mgr := &Manager{nexus: nexus, client: client}
+	resources, err := mgr.GetRequiredResources()
+	assert.NoError(t, err)
+	assert.Len(t, resources, 1)
+	// the field must be consumed, not left for the next reconcile to re-trigger
+	assert.Empty(t, nexus.Spec.Persistence.RestoreFromSnapshot)
+	assert.NotNil(t, nexus.Spec.Persistence.DataSource)
+	assert.Equal(t, "backup-snap", nexus.Spec.Persistence.DataSource.Name)
+}
@@ -0,0 +1,116 @@
+// Copyright 2020 Nexus Operator and/or its authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test holds helpers shared by this operator's test suites.
+package test
+
+import (
+	ctx "context"
+
+	obv1alpha1 "github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/m88i/nexus-operator/apis/apps/v1alpha1"
+)
+
+// FakeClient wraps the controller-runtime fake client, additionally allowing
+// tests to force the next request to fail with an arbitrary error. This is
+// useful to exercise error-handling paths that the fake client itself can't
+// otherwise produce (e.g. a 500 from the API server).
+type FakeClient struct {
+	client.Client
+	mockError error
+}
+
+// SetMockErrorForOneRequest makes the next Get, List, Create, Update or
+// Delete call to this client return err. Other operations (Patch,
+// DeleteAllOf, status writes) neither return nor consume it. Subsequent
+// calls are unaffected.
+func (f *FakeClient) SetMockErrorForOneRequest(err error) {
+	f.mockError = err
+}
+
+func (f *FakeClient) consumeMockError() error {
+	err := f.mockError
+	f.mockError = nil
+	return err
+}
+
+// Get implements client.Client.
+func (f *FakeClient) Get(c ctx.Context, key client.ObjectKey, obj client.Object) error {
+	if err := f.consumeMockError(); err != nil {
+		return err
+	}
+	return f.Client.Get(c, key, obj)
+}
+
+// List implements client.Client.
+func (f *FakeClient) List(c ctx.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if err := f.consumeMockError(); err != nil {
+		return err
+	}
+	return f.Client.List(c, list, opts...)
+}
+
+// Create implements client.Client.
+func (f *FakeClient) Create(c ctx.Context, obj client.Object, opts ...client.CreateOption) error {
+	if err := f.consumeMockError(); err != nil {
+		return err
+	}
+	return f.Client.Create(c, obj, opts...)
+}
+
+// Update implements client.Client.
+func (f *FakeClient) Update(c ctx.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if err := f.consumeMockError(); err != nil {
+		return err
+	}
+	return f.Client.Update(c, obj, opts...)
+}
+
+// Delete implements client.Client.
+func (f *FakeClient) Delete(c ctx.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if err := f.consumeMockError(); err != nil {
+		return err
+	}
+	return f.Client.Delete(c, obj, opts...)
+}
+
+// FakeClientBuilder builds a FakeClient with this operator's scheme already
+// registered.
+type FakeClientBuilder struct {
+	objs []client.Object
+}
+
+// NewFakeClientBuilder creates a FakeClientBuilder seeded with the given
+// objects.
+func NewFakeClientBuilder(objs ...client.Object) *FakeClientBuilder {
+	return &FakeClientBuilder{objs: objs}
+}
+
+// Build returns a ready-to-use FakeClient.
+func (b *FakeClientBuilder) Build() *FakeClient {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+	_ = snapshotv1.AddToScheme(scheme)
+	_ = obv1alpha1.AddToScheme(scheme)
+	return &FakeClient{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(b.objs...).Build(),
+	}
+}